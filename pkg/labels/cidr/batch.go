@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+// GetCIDRLabelsForPrefixes is the batch form of GetCIDRLabels. It processes
+// all of prefixes in a single pass, shortest prefix first, building a
+// throwaway trie over just this batch so that the ancestor labels computed
+// for a broad prefix (e.g. 10.0.0.0/8) are directly reused - not merely
+// cache-hit - by its descendants in the same batch (e.g. 10.1.0.0/16 and
+// 10.2.0.0/16 share the identical /8 node and its precomputed label)
+// without ever taking globalTrie's lock.
+//
+// This is intended for callers such as ipcache that otherwise have to call
+// GetCIDRLabels in a loop, e.g. to label a large CiliumCIDRGroup, paying a
+// lock acquisition and independent top-to-bottom recompute per prefix.
+func GetCIDRLabelsForPrefixes(prefixes []netip.Prefix) map[netip.Prefix]labels.Labels {
+	sorted := make([]netip.Prefix, len(prefixes))
+	copy(sorted, prefixes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	bt := newBatchTrie()
+	result := make(map[netip.Prefix]labels.Labels, len(sorted))
+	for _, p := range sorted {
+		if _, ok := result[p]; ok {
+			continue
+		}
+
+		lbls := bt.labelsFor(p)
+		addWorldLabel(p.Addr(), lbls)
+		addGeoIPLabels(p.Addr(), lbls)
+		result[p] = lbls
+	}
+
+	return result
+}
+
+// batchNode is a node of a batchTrie. Unlike trieNode it has no parent
+// pointer: batchTrie always walks top-down from the root and accumulates
+// labels as it goes, so there's no need to walk back up afterwards.
+type batchNode struct {
+	children [2]*batchNode
+	label    labels.Label
+	hasLabel bool
+}
+
+// batchTrie is a trie scoped to a single GetCIDRLabelsForPrefixes call.
+// Unlike the persistent globalTrie, it only lives for the duration of that
+// call, so it adds no long-term memory cost, but it still lets every
+// prefix in the batch directly reuse the node (and the label stored in it)
+// computed for a shared ancestor by an earlier prefix in the same batch.
+type batchTrie struct {
+	root4 *batchNode
+	root6 *batchNode
+}
+
+func newBatchTrie() *batchTrie {
+	return &batchTrie{
+		root4: &batchNode{hasLabel: true, label: maskedIPToLabel(netip.IPv4Unspecified(), 0)},
+		root6: &batchNode{hasLabel: true, label: maskedIPToLabel(netip.IPv6Unspecified(), 0)},
+	}
+}
+
+// labelsFor walks down to the node for p, creating any missing nodes and
+// computing their label the first time they're reached, and returns the
+// union of every node's label from the root down to p - the same set
+// GetCIDRLabels computes for p, minus the reserved:world and GeoIP labels
+// which the caller adds separately.
+func (t *batchTrie) labelsFor(p netip.Prefix) labels.Labels {
+	addr := p.Addr()
+	bits := addr.AsSlice()
+	ones := p.Bits()
+
+	// Like GetCIDRLabels, a /0 carries no CIDR label of its own, only the
+	// reserved:world (and GeoIP) labels the caller adds.
+	if ones == 0 {
+		return make(labels.Labels, 2)
+	}
+
+	root := t.root4
+	if !addr.Is4() {
+		root = t.root6
+	}
+
+	lbls := make(labels.Labels, ones+2)
+	node := root
+	for i := 0; i <= ones; i++ {
+		if i > 0 {
+			bit := bitAt(bits, i-1)
+			child := node.children[bit]
+			if child == nil {
+				child = &batchNode{}
+				node.children[bit] = child
+			}
+			node = child
+		}
+		if !node.hasLabel {
+			node.label = maskedIPToLabel(netip.PrefixFrom(addr, i).Masked().Addr(), i)
+			node.hasLabel = true
+		}
+		lbls[node.label.Key] = node.label
+	}
+
+	return lbls
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func TestGetCIDRLabelsForPrefixesMatchesGetCIDRLabels(t *testing.T) {
+	prefixes := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("10.2.0.0/16"),
+		netip.MustParsePrefix("192.168.1.0/24"),
+	}
+
+	got := GetCIDRLabelsForPrefixes(prefixes)
+	if len(got) != len(prefixes) {
+		t.Fatalf("got %d results, want %d", len(got), len(prefixes))
+	}
+
+	for _, p := range prefixes {
+		want := GetCIDRLabels(p)
+		gotLbls, ok := got[p]
+		if !ok {
+			t.Fatalf("missing result for %s", p)
+		}
+		if len(gotLbls) != len(want) {
+			t.Errorf("GetCIDRLabelsForPrefixes(%s) = %v, want %v", p, gotLbls, want)
+			continue
+		}
+		for key, lbl := range want {
+			if gotLbls[key] != lbl {
+				t.Errorf("GetCIDRLabelsForPrefixes(%s)[%q] = %v, want %v", p, key, gotLbls[key], lbl)
+			}
+		}
+	}
+}
+
+func TestGetCIDRLabelsForPrefixesSharesAncestorLabel(t *testing.T) {
+	prefixes := []netip.Prefix{
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("10.2.0.0/16"),
+	}
+	got := GetCIDRLabelsForPrefixes(prefixes)
+
+	const eightKey = "10.0.0.0/8"
+	l1, ok1 := got[prefixes[0]][eightKey]
+	l2, ok2 := got[prefixes[1]][eightKey]
+	if !ok1 || !ok2 {
+		t.Fatalf("expected both results to carry the %s ancestor label", eightKey)
+	}
+	if l1 != l2 {
+		t.Errorf("expected the shared /8 ancestor label to be identical, got %v and %v", l1, l2)
+	}
+}
+
+func benchPrefixes(n int) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, n)
+	for i := 0; i < n; i++ {
+		a := byte(i >> 8)
+		b := byte(i)
+		prefixes = append(prefixes, netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 0, a, b}), 32))
+	}
+	return prefixes
+}
+
+// BenchmarkGetCIDRLabelsForPrefixes exercises a realistic CiliumCIDRGroup
+// workload: 10k /32s within a single /16, so every entry shares the same
+// /8-through-/16 ancestor chain.
+func BenchmarkGetCIDRLabelsForPrefixes(b *testing.B) {
+	prefixes := benchPrefixes(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = GetCIDRLabelsForPrefixes(prefixes)
+	}
+}
+
+// BenchmarkGetCIDRLabelsLoop is the naive "call GetCIDRLabels in a loop"
+// baseline GetCIDRLabelsForPrefixes replaces, for comparison.
+func BenchmarkGetCIDRLabelsLoop(b *testing.B) {
+	prefixes := benchPrefixes(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		results := make(map[netip.Prefix]labels.Labels, len(prefixes))
+		for _, p := range prefixes {
+			results[p] = GetCIDRLabels(p)
+		}
+	}
+}
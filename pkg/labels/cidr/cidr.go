@@ -8,12 +8,8 @@ import (
 	"net/netip"
 	"strconv"
 	"strings"
-	"sync"
-
-	"github.com/hashicorp/golang-lru/v2/simplelru"
 
 	"github.com/cilium/cilium/pkg/labels"
-	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/option"
 )
 
@@ -53,14 +49,53 @@ func maskedIPToLabel(ip netip.Addr, prefix int) labels.Label {
 	return labels.Label{Key: str.String(), Source: labels.LabelSourceCIDR}
 }
 
-// IPStringToLabel parses a string and returns it as a CIDR label.
+// ParseMode selects how IPStringToLabelWithMode interprets the IP address
+// strings it's given.
+type ParseMode int
+
+const (
+	// ParseStrict is Go's netip parsing behavior (Go 1.17+): it rejects
+	// IPv4 octets with leading zeros.
+	ParseStrict ParseMode = iota
+
+	// ParseLegacy additionally accepts zero-padded IPv4 octets, e.g.
+	// "010.0.0.1", by canonicalizing each octet that has a leading zero as
+	// octal before parsing, e.g. "010" -> "8". This matches legacy parsers
+	// such as inet_aton and is needed because Cilium ingests CIDR strings
+	// from upstream sources (CRDs, kube-apiserver, external policy
+	// engines) where zero-padded octets still appear.
+	ParseLegacy
+)
+
+// IPStringToLabel parses a string and returns it as a CIDR label, using
+// strict (current Go) parsing rules.
 //
 // If ip is not a valid IP address or CIDR Prefix, returns an error.
 func IPStringToLabel(ip string) (labels.Label, error) {
+	return IPStringToLabelWithMode(ip, ParseStrict)
+}
+
+// IPStringToLabelWithMode parses a string and returns it as a CIDR label,
+// applying the given ParseMode to determine whether legacy zero-padded IPv4
+// octets are tolerated.
+//
+// If ip is not a valid IP address or CIDR Prefix, returns an error.
+func IPStringToLabelWithMode(ip string, mode ParseMode) (labels.Label, error) {
+	if mode == ParseLegacy {
+		normalized, err := normalizeLegacyIPv4(ip)
+		if err != nil {
+			return labels.Label{}, err
+		}
+		ip = normalized
+	}
+
 	// factored out of netip.ParsePrefix to avoid allocating an empty netip.Prefix in case it's
 	// an IP and not a CIDR.
 	i := strings.LastIndexByte(ip, '/')
 	if i < 0 {
+		if strings.IndexByte(ip, '-') >= 0 {
+			return labels.Label{}, fmt.Errorf("%q is an IP range, use IPRangeStringToLabels instead", ip)
+		}
 		parsedIP, err := netip.ParseAddr(ip)
 		if err != nil {
 			return labels.Label{}, fmt.Errorf("%q is not an IP address: %w", ip, err)
@@ -75,6 +110,163 @@ func IPStringToLabel(ip string) (labels.Label, error) {
 	}
 }
 
+// normalizeLegacyIPv4 rewrites the IPv4 portion of ip (which may be a bare
+// address or a CIDR) so that any zero-padded octet is canonicalized to its
+// octal value, e.g. "010.0.0.1" -> "8.0.0.1". IPv6 addresses (identified by
+// the presence of ':') are passed through unchanged, since the leading-zero
+// ambiguity this resolves is specific to dotted-decimal notation.
+func normalizeLegacyIPv4(ip string) (string, error) {
+	if strings.IndexByte(ip, ':') >= 0 {
+		return ip, nil
+	}
+
+	suffix := ""
+	addrPart := ip
+	if i := strings.LastIndexByte(ip, '/'); i >= 0 {
+		addrPart, suffix = ip[:i], ip[i:]
+	}
+
+	octets := strings.Split(addrPart, ".")
+	if len(octets) != 4 {
+		// Not dotted-decimal (e.g. a dashed IP range, rejected by the
+		// caller, or simply invalid); leave it unchanged.
+		return ip, nil
+	}
+
+	for idx, octet := range octets {
+		normalized, err := normalizeLegacyOctet(octet)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid legacy IPv4 address: octet %q: %w", ip, octet, err)
+		}
+		octets[idx] = normalized
+	}
+
+	return strings.Join(octets, ".") + suffix, nil
+}
+
+// normalizeLegacyOctet canonicalizes a single dotted-decimal octet. Octets
+// without a leading zero are returned unchanged (still validated as 0-255).
+// Octets with a leading zero are parsed as octal, e.g. "010" -> "8". A
+// zero-padded octet containing an invalid octal digit, e.g. "09", is
+// rejected rather than silently falling back to decimal, since that would
+// make the same string parse to two different addresses depending on
+// context.
+func normalizeLegacyOctet(octet string) (string, error) {
+	if octet == "" {
+		return "", fmt.Errorf("empty octet")
+	}
+	if octet[0] != '0' || len(octet) == 1 {
+		if _, err := strconv.ParseUint(octet, 10, 8); err != nil {
+			return "", err
+		}
+		return octet, nil
+	}
+
+	value, err := strconv.ParseUint(octet, 8, 8)
+	if err != nil {
+		return "", fmt.Errorf("leading zero but not valid octal: %w", err)
+	}
+	return strconv.FormatUint(value, 10), nil
+}
+
+// IPRangeStringToLabels parses a dashed IP-range string, e.g.
+// "10.0.0.5-10.0.0.37" or "2001:db8::1-2001:db8::ff", and returns the
+// labels for the minimal set of CIDRs that cover the inclusive range, each
+// expanded through GetCIDRLabels so the result also carries every covering
+// prefix's ancestor CIDRs and the reserved:world label - the same way a
+// policy selector matches a single CIDR today.
+//
+// This allows identities to be expressed for address pools (e.g. DHCP
+// ranges) that don't happen to align on a prefix boundary, by decomposing
+// the range into the CIDRs that do.
+func IPRangeStringToLabels(ipRange string) (labels.Labels, error) {
+	i := strings.IndexByte(ipRange, '-')
+	if i < 0 {
+		return nil, fmt.Errorf("%q is not an IP range", ipRange)
+	}
+
+	start, err := netip.ParseAddr(ipRange[:i])
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid range start: %w", ipRange[:i], err)
+	}
+	end, err := netip.ParseAddr(ipRange[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid range end: %w", ipRange[i+1:], err)
+	}
+	if start.Is4() != end.Is4() {
+		return nil, fmt.Errorf("range start %q and end %q are not the same address family", start, end)
+	}
+	if end.Less(start) {
+		return nil, fmt.Errorf("range start %q must not be after range end %q", start, end)
+	}
+
+	prefixes := rangeToPrefixes(start, end)
+	lbls := make(labels.Labels)
+	for _, p := range prefixes {
+		for key, lbl := range GetCIDRLabels(p) {
+			lbls[key] = lbl
+		}
+	}
+	return lbls, nil
+}
+
+// rangeToPrefixes decomposes the inclusive range [start, end] into the
+// minimal set of CIDR prefixes covering it. At each step it emits the
+// largest prefix aligned on start that does not extend past end, then
+// advances start past that prefix and repeats.
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	bits := start.BitLen()
+	var prefixes []netip.Prefix
+
+	for !end.Less(start) {
+		// The largest prefix aligned on start is bounded by the number of
+		// trailing zero host bits in start: a /n prefix starting at start
+		// is only valid if the low (bits-n) bits of start are all zero.
+		prefixLen := bits
+		for prefixLen > 0 && alignedBit(start, prefixLen-1) {
+			prefixLen--
+		}
+
+		// Grow the prefix back up (shrink prefixLen) until its last address
+		// no longer overshoots end.
+		for prefixLen < bits && end.Less(lastAddr(netip.PrefixFrom(start, prefixLen))) {
+			prefixLen++
+		}
+
+		p := netip.PrefixFrom(start, prefixLen)
+		prefixes = append(prefixes, p)
+
+		last := lastAddr(p)
+		if last == end {
+			break
+		}
+		start = last.Next()
+	}
+
+	return prefixes
+}
+
+// alignedBit reports whether bit i (0-indexed from the most significant
+// bit) of addr is zero. Only that one bit is checked: addr is aligned on a
+// prefix boundary of length i only if bit i and every lower bit are zero.
+func alignedBit(addr netip.Addr, i int) bool {
+	b := addr.AsSlice()
+	byteIdx := i / 8
+	bitIdx := 7 - (i % 8)
+	return b[byteIdx]&(1<<bitIdx) == 0
+}
+
+// lastAddr returns the last address covered by prefix p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	ones := p.Bits()
+	for i := ones; i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - (i % 8))
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
 // GetCIDRLabels turns a CIDR into a set of labels representing the cidr itself
 // and all broader CIDRS which include the specified CIDR in them. For example:
 // CIDR: 10.0.0.0/8 =>
@@ -84,12 +276,13 @@ func IPStringToLabel(ip string) (labels.Label, error) {
 //	"cidr:0.0.0.0/2",  "cidr:0.0.0.0/1",  "cidr:0.0.0.0/0"
 //
 // The identity reserved:world is always added as it includes any CIDR.
+//
+// The ancestor prefixes (e.g. 10.0.0.0/8, 10.0.0.0/7, ...) are served from
+// globalTrie, which shares the computed label of a given ancestor prefix
+// across every descendant prefix that was ever looked up (e.g. 10.0.0.0/8,
+// 10.1.0.0/16 and 10.2.0.0/16 all reuse the same /7 and broader nodes)
+// instead of recomputing and re-caching an independent copy per prefix.
 func GetCIDRLabels(prefix netip.Prefix) labels.Labels {
-	once.Do(func() {
-		// simplelru.NewLRU fails only when given a negative size, so we can skip the error check
-		cidrLabelsCache, _ = simplelru.NewLRU[netip.Prefix, []labels.Label](cidrLabelsCacheMaxSize, nil)
-	})
-
 	addr := prefix.Addr()
 	ones := prefix.Bits()
 	lbls := make(labels.Labels, 1 /* this CIDR */ +ones /* the prefixes */ +1 /*world label*/)
@@ -100,37 +293,17 @@ func GetCIDRLabels(prefix netip.Prefix) labels.Labels {
 	// specified prefix length.
 	if ones == 0 {
 		addWorldLabel(addr, lbls)
+		addGeoIPLabels(addr, lbls)
 		return lbls
 	}
 
-	computeCIDRLabels(
-		cidrLabelsCache,
-		lbls,
-		nil, // avoid allocating space for the intermediate results until we need it
-		addr,
-		ones,
-		0,
-	)
+	globalTrie.computeAndInsert(addr, ones, lbls)
 	addWorldLabel(addr, lbls)
+	addGeoIPLabels(addr, lbls)
 
 	return lbls
 }
 
-var (
-	// cidrLabelsCache stores the partial computations for CIDR labels.
-	// This both avoids repeatedly computing the prefixes and makes sure the
-	// CIDR strings are reused to reduce memory usage.
-	// Stored in a lru map to limit memory usage.
-	//
-	// Stores e.g. for prefix "10.0.0.0/8" the labels ["10.0.0.0/8", ..., "0.0.0.0/0"].
-	cidrLabelsCache *simplelru.LRU[netip.Prefix, []labels.Label]
-
-	// mutex to serialize concurrent accesses to the cidrLabelsCache.
-	mu lock.Mutex
-)
-
-const cidrLabelsCacheMaxSize = 16384
-
 func addWorldLabel(addr netip.Addr, lbls labels.Labels) {
 	switch {
 	case !option.Config.IsDualStack():
@@ -143,50 +316,7 @@ func addWorldLabel(addr netip.Addr, lbls labels.Labels) {
 }
 
 var (
-	once sync.Once
-
 	worldLabelNonDualStack = labels.Label{Key: labels.IDNameWorld, Source: labels.LabelSourceReserved}
 	worldLabelV4           = labels.Label{Source: labels.LabelSourceReserved, Key: labels.IDNameWorldIPv4}
 	worldLabelV6           = labels.Label{Source: labels.LabelSourceReserved, Key: labels.IDNameWorldIPv6}
 )
-
-func computeCIDRLabels(cache *simplelru.LRU[netip.Prefix, []labels.Label], lbls labels.Labels, results []labels.Label, addr netip.Addr, ones, i int) []labels.Label {
-	if i > ones {
-		return results
-	}
-
-	prefix := netip.PrefixFrom(addr, i)
-
-	mu.Lock()
-	cachedLbls, ok := cache.Get(prefix)
-	mu.Unlock()
-	if ok {
-		for _, lbl := range cachedLbls {
-			lbls[lbl.Key] = lbl
-		}
-		if results == nil {
-			return cachedLbls
-		} else {
-			return append(results, cachedLbls...)
-		}
-	}
-
-	// Compute the label for this prefix (e.g. "cidr:10.0.0.0/8")
-	prefixLabel := maskedIPToLabel(prefix.Masked().Addr(), i)
-	lbls[prefixLabel.Key] = prefixLabel
-
-	// Keep computing the rest (e.g. "cidr:10.0.0.0/7", ...).
-	results = computeCIDRLabels(
-		cache,
-		lbls,
-		append(results, prefixLabel),
-		addr, ones, i+1,
-	)
-
-	// Cache the resulting labels derived from this prefix, e.g. /8, /7, ...
-	mu.Lock()
-	cache.Add(prefix, results[i:])
-	mu.Unlock()
-
-	return results
-}
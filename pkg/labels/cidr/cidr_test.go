@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import "testing"
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       []string
+	}{
+		{"10.0.0.0", "10.0.0.0", []string{"10.0.0.0/32"}},
+		{"10.0.0.0", "10.0.0.3", []string{"10.0.0.0/30"}},
+		{
+			"10.0.0.5", "10.0.0.37",
+			[]string{
+				"10.0.0.5/32", "10.0.0.6/31", "10.0.0.8/29", "10.0.0.16/28",
+				"10.0.0.32/30", "10.0.0.36/31",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		lbls, err := IPRangeStringToLabels(tt.start + "-" + tt.end)
+		if err != nil {
+			t.Fatalf("IPRangeStringToLabels(%s-%s): unexpected error: %v", tt.start, tt.end, err)
+		}
+
+		for _, want := range tt.want {
+			if _, ok := lbls[want]; !ok {
+				t.Errorf("IPRangeStringToLabels(%s-%s): missing covering prefix label %q in %v", tt.start, tt.end, want, lbls)
+			}
+		}
+
+		// The world label must also be present, the same way a single
+		// equivalent CIDR policy entry would bring it in.
+		if _, ok := lbls[worldLabelNonDualStack.Key]; !ok {
+			if _, ok := lbls[worldLabelV4.Key]; !ok {
+				t.Errorf("IPRangeStringToLabels(%s-%s): missing reserved:world label", tt.start, tt.end)
+			}
+		}
+	}
+}
+
+func TestIPRangeStringToLabelsRejectsReversedRange(t *testing.T) {
+	if _, err := IPRangeStringToLabels("10.0.0.37-10.0.0.5"); err == nil {
+		t.Fatalf("expected an error for a reversed range")
+	}
+}
+
+func TestIPRangeStringToLabelsRejectsMixedFamily(t *testing.T) {
+	if _, err := IPRangeStringToLabels("10.0.0.1-2001:db8::1"); err == nil {
+		t.Fatalf("expected an error for a mixed-family range")
+	}
+}
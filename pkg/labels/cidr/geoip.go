@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "labels-cidr")
+
+const (
+	// LabelGeoIPCountry is the label key used for the country derived from
+	// a GeoIP database lookup.
+	LabelGeoIPCountry = "geoip-country"
+	// LabelGeoIPContinent is the label key used for the continent derived
+	// from a GeoIP database lookup.
+	LabelGeoIPContinent = "geoip-continent"
+
+	// geoIPCacheMaxSize bounds the LRU cache of GeoIP lookup results. GeoIP
+	// lookups are keyed by individual address rather than CIDR, so this is
+	// sized to comfortably cover the working set of addresses seen in a
+	// single ipcache reconciliation pass without growing unbounded.
+	geoIPCacheMaxSize = 16384
+)
+
+// geoIPRecord is the subset of a MaxMind GeoLite2/GeoIP2 Country database
+// record that we care about.
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+}
+
+type geoIPResult struct {
+	country   string
+	continent string
+	ok        bool
+}
+
+// geoIPDB wraps a MaxMind reader with a cache of recent lookups and a
+// watcher that reloads the reader whenever the on-disk database changes.
+type geoIPDB struct {
+	mu     lock.RWMutex
+	reader *maxminddb.Reader
+	cache  *simplelru.LRU[netip.Addr, geoIPResult]
+
+	watcher *fsnotify.Watcher
+}
+
+var (
+	geoIPOnce sync.Once
+	geoIP     *geoIPDB
+)
+
+// getGeoIPDB lazily opens the GeoIP database configured via
+// option.Config.GeoIPDatabasePath, if any, and starts watching it for
+// updates. Returns nil if no database is configured or it failed to load.
+func getGeoIPDB() *geoIPDB {
+	path := option.Config.GeoIPDatabasePath
+	if path == "" {
+		return nil
+	}
+
+	geoIPOnce.Do(func() {
+		db, err := loadGeoIPDB(path)
+		if err != nil {
+			log.WithError(err).WithField(logfields.Path, path).Error("Failed to load GeoIP database")
+			return
+		}
+		geoIP = db
+		geoIP.watch(path)
+	})
+
+	return geoIP
+}
+
+func loadGeoIPDB(path string) (*geoIPDB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, _ := simplelru.NewLRU[netip.Addr, geoIPResult](geoIPCacheMaxSize, nil)
+	return &geoIPDB{
+		reader: reader,
+		cache:  cache,
+	}, nil
+}
+
+// watch reloads the database whenever it changes on disk, e.g. when it is
+// atomically replaced by a database update job. It watches path's parent
+// directory rather than path itself: an update job typically replaces the
+// file by renaming a new one over it, which leaves a watch on the old
+// inode stale and silently stops delivering further events at that path,
+// whereas the directory's watch keeps seeing every replacement.
+func (db *geoIPDB) watch(path string) {
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).Warning("Failed to watch GeoIP database for updates")
+		return
+	}
+	if err := watcher.Add(dir); err != nil {
+		log.WithError(err).WithField(logfields.Path, dir).Warning("Failed to watch GeoIP database for updates")
+		watcher.Close()
+		return
+	}
+	db.watcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+				reader, err := maxminddb.Open(path)
+				if err != nil {
+					log.WithError(err).WithField(logfields.Path, path).Error("Failed to reload GeoIP database")
+					continue
+				}
+				db.mu.Lock()
+				old := db.reader
+				db.reader = reader
+				db.cache.Purge()
+				db.mu.Unlock()
+				old.Close()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(err).Warning("Error watching GeoIP database")
+			}
+		}
+	}()
+}
+
+func (db *geoIPDB) lookup(addr netip.Addr) geoIPResult {
+	db.mu.Lock()
+	if cached, ok := db.cache.Get(addr); ok {
+		db.mu.Unlock()
+		return cached
+	}
+	db.mu.Unlock()
+
+	// Hold the read lock for the whole Lookup call, not just the reader
+	// pointer read: watch() swaps db.reader under the write lock and then
+	// closes the old one, which unmaps the backing file, so a Lookup still
+	// using the old reader after the swap could otherwise dereference
+	// unmapped memory. Holding the read lock keeps the swap, and so the
+	// close, from happening until this Lookup is done.
+	var record geoIPRecord
+	db.mu.RLock()
+	err := db.reader.Lookup(addr.AsSlice(), &record)
+	db.mu.RUnlock()
+
+	var result geoIPResult
+	if err == nil && record.Country.ISOCode != "" {
+		result = geoIPResult{
+			country:   record.Country.ISOCode,
+			continent: record.Continent.Code,
+			ok:        true,
+		}
+	}
+
+	db.mu.Lock()
+	db.cache.Add(addr, result)
+	db.mu.Unlock()
+
+	return result
+}
+
+// LookupCountry returns the ISO country code for addr according to the
+// configured GeoIP database, and whether a match was found. It returns
+// false if no GeoIP database is configured.
+func LookupCountry(addr netip.Addr) (string, bool) {
+	db := getGeoIPDB()
+	if db == nil {
+		return "", false
+	}
+	result := db.lookup(addr)
+	return result.country, result.ok
+}
+
+// addGeoIPLabels augments lbls with reserved:geoip-country and
+// reserved:geoip-continent labels for addr, if a GeoIP database is
+// configured and has a match for it.
+func addGeoIPLabels(addr netip.Addr, lbls labels.Labels) {
+	db := getGeoIPDB()
+	if db == nil {
+		return
+	}
+
+	result := db.lookup(addr)
+	if !result.ok {
+		return
+	}
+
+	countryLabel := labels.Label{Key: LabelGeoIPCountry, Value: result.country, Source: labels.LabelSourceReserved}
+	lbls[countryLabel.Key] = countryLabel
+
+	if result.continent != "" {
+		continentLabel := labels.Label{Key: LabelGeoIPContinent, Value: result.continent, Source: labels.LabelSourceReserved}
+		lbls[continentLabel.Key] = continentLabel
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+
+	"github.com/cilium/cilium/pkg/option"
+)
+
+// withGeoIPDB points the package-level GeoIP singleton at db for the
+// duration of the test, without going through loadGeoIPDB (and therefore
+// without needing a real .mmdb file on disk): the singleton's sync.Once is
+// pre-fired so getGeoIPDB returns db directly as long as a database path is
+// configured.
+func withGeoIPDB(t *testing.T, db *geoIPDB) {
+	t.Helper()
+
+	geoIPOnce = sync.Once{}
+	geoIPOnce.Do(func() {})
+	geoIP = db
+	option.Config.GeoIPDatabasePath = "test.mmdb"
+
+	t.Cleanup(func() {
+		option.Config.GeoIPDatabasePath = ""
+		geoIP = nil
+		geoIPOnce = sync.Once{}
+	})
+}
+
+func TestLookupLabelsForAddrAndPrefixIncludeGeoIPLabels(t *testing.T) {
+	addr := netip.MustParseAddr("203.0.113.5")
+
+	cache, _ := simplelru.NewLRU[netip.Addr, geoIPResult](geoIPCacheMaxSize, nil)
+	cache.Add(addr, geoIPResult{country: "US", continent: "NA", ok: true})
+	withGeoIPDB(t, &geoIPDB{cache: cache})
+
+	addrLbls := LookupLabelsForAddr(addr)
+	if got := addrLbls[LabelGeoIPCountry].Value; got != "US" {
+		t.Errorf("LookupLabelsForAddr: geoip-country = %q, want %q", got, "US")
+	}
+	if got := addrLbls[LabelGeoIPContinent].Value; got != "NA" {
+		t.Errorf("LookupLabelsForAddr: geoip-continent = %q, want %q", got, "NA")
+	}
+
+	prefixLbls := LookupLabelsForPrefix(netip.PrefixFrom(addr, 32))
+	if got := prefixLbls[LabelGeoIPCountry].Value; got != "US" {
+		t.Errorf("LookupLabelsForPrefix: geoip-country = %q, want %q", got, "US")
+	}
+	if got := prefixLbls[LabelGeoIPContinent].Value; got != "NA" {
+		t.Errorf("LookupLabelsForPrefix: geoip-continent = %q, want %q", got, "NA")
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import "testing"
+
+func TestIPStringToLabelWithModeStrictRejectsLeadingZero(t *testing.T) {
+	if _, err := IPStringToLabelWithMode("010.0.0.1", ParseStrict); err == nil {
+		t.Fatalf("expected ParseStrict to reject a zero-padded octet")
+	}
+}
+
+func TestIPStringToLabelWithModeLegacyCanonicalizesOctal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"010.0.0.1", "8.0.0.1/32"},
+		{"10.0.0.1", "10.0.0.1/32"},
+		{"010.0.0.0/8", "8.0.0.0/8"},
+		{"0.0.0.0", "0.0.0.0/32"},
+	}
+
+	for _, tt := range tests {
+		lbl, err := IPStringToLabelWithMode(tt.in, ParseLegacy)
+		if err != nil {
+			t.Fatalf("IPStringToLabelWithMode(%q, ParseLegacy): unexpected error: %v", tt.in, err)
+		}
+		if lbl.Key != tt.want {
+			t.Fatalf("IPStringToLabelWithMode(%q, ParseLegacy) = %q, want %q", tt.in, lbl.Key, tt.want)
+		}
+	}
+}
+
+func TestIPStringToLabelWithModeLegacyRejectsInvalidOctal(t *testing.T) {
+	// "09" looks zero-padded but isn't a valid octal number; it must be
+	// rejected rather than silently falling back to decimal 9.
+	if _, err := IPStringToLabelWithMode("09.0.0.1", ParseLegacy); err == nil {
+		t.Fatalf("expected ParseLegacy to reject %q", "09.0.0.1")
+	}
+}
+
+func TestIPStringToLabelWithModeLegacyLeavesIPv6Alone(t *testing.T) {
+	lbl, err := IPStringToLabelWithMode("2001:db8::1", ParseLegacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lbl.Key != "2001-db8--1/128" {
+		t.Fatalf("got %q, want %q", lbl.Key, "2001-db8--1/128")
+	}
+}
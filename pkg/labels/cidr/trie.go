@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// trieNode is a single node of a binary (patricia) trie keyed by the bits
+// of an IP prefix. Each node that corresponds to a prefix that has been
+// labeled carries that label plus a pointer to its parent, so that walking
+// from a node up to the root visits exactly the ancestor CIDRs of that
+// prefix without recomputing anything.
+type trieNode struct {
+	children [2]*trieNode
+	parent   *trieNode
+
+	label    labels.Label
+	hasLabel bool
+}
+
+// cidrTrie indexes every CIDR that has ever been labeled, keyed by prefix,
+// so that ancestor labels are shared in memory across all descendants
+// instead of being recomputed and re-allocated per prefix (10.0.0.0/8,
+// 10.1.0.0/16 and 10.2.0.0/16 all reuse the same /7-and-broader nodes), and
+// so that labels can also be looked up in the reverse direction, i.e. given
+// an address, find the CIDRs that contain it.
+//
+// Node count is bounded by maxTrieNodes so the trie cannot grow without
+// limit over the lifetime of a process: once the cap is reached, new
+// branches are still computed and returned correctly to the caller, but are
+// no longer linked into the trie, so they don't consume memory beyond the
+// lifetime of that one call and don't participate in future sharing or
+// reverse lookups. In practice the number of distinct CIDRs ever labeled is
+// bounded by configured policy, so this is expected to be a backstop rather
+// than a commonly hit limit.
+type cidrTrie struct {
+	mu    lock.Mutex
+	root4 *trieNode
+	root6 *trieNode
+	size  int
+}
+
+// maxTrieNodes bounds the memory globalTrie can grow to. The previous
+// LRU-based cache bounded the equivalent working set to 16384 *prefixes*;
+// this cap counts nodes instead, 64 for each of those 16384 prefixes. Each
+// prefix can touch up to 32 (v4) or 128 (v6) ancestor nodes, but ancestors
+// are shared, so this allows for substantially more distinct CIDRs than the
+// old cache in practice while still being a firm ceiling.
+const maxTrieNodes = 1 << 20
+
+var globalTrie = &cidrTrie{
+	root4: &trieNode{hasLabel: true, label: maskedIPToLabel(netip.IPv4Unspecified(), 0)},
+	root6: &trieNode{hasLabel: true, label: maskedIPToLabel(netip.IPv6Unspecified(), 0)},
+}
+
+func (t *cidrTrie) rootFor(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return t.root4
+	}
+	return t.root6
+}
+
+// computeAndInsert walks the trie from the root down to the node for
+// netip.PrefixFrom(addr, ones), creating any missing nodes along the way
+// (subject to maxTrieNodes), lazily computing each node's label the first
+// time it's reached, and merging every ancestor's label into lbls as it
+// goes - this is the same traversal GetCIDRLabels used to do by recursing
+// and caching each level independently, except ancestor nodes shared by an
+// earlier call are reused directly instead of recomputed.
+func (t *cidrTrie) computeAndInsert(addr netip.Addr, ones int, lbls labels.Labels) {
+	bits := addr.AsSlice()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := t.rootFor(addr)
+	for i := 0; i <= ones; i++ {
+		if i > 0 {
+			bit := bitAt(bits, i-1)
+			child := node.children[bit]
+			if child == nil {
+				child = &trieNode{parent: node}
+				if t.size < maxTrieNodes {
+					node.children[bit] = child
+					t.size++
+				}
+			}
+			node = child
+		}
+		if !node.hasLabel {
+			node.label = maskedIPToLabel(netip.PrefixFrom(addr, i).Masked().Addr(), i)
+			node.hasLabel = true
+		}
+		lbls[node.label.Key] = node.label
+	}
+}
+
+// lookup walks from the root down to the node for addr (or as far as the
+// trie extends), returning the deepest node reached.
+func (t *cidrTrie) lookup(addr netip.Addr, maxBits int) *trieNode {
+	bits := addr.AsSlice()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := t.rootFor(addr)
+	for i := 0; i < maxBits; i++ {
+		child := node.children[bitAt(bits, i)]
+		if child == nil {
+			break
+		}
+		node = child
+	}
+	return node
+}
+
+// bitAt returns bit i (0-indexed from the most significant bit) of b.
+func bitAt(b []byte, i int) int {
+	return int(b[i/8]>>(7-(i%8))) & 1
+}
+
+// ancestorLabels walks from node up to the root, collecting every label
+// found along the way. The result matches what GetCIDRLabels would produce
+// for the prefix node was reached with, minus the reserved:world label
+// which callers of the trie add separately.
+func ancestorLabels(node *trieNode) labels.Labels {
+	lbls := make(labels.Labels)
+	for n := node; n != nil; n = n.parent {
+		if n.hasLabel {
+			lbls[n.label.Key] = n.label
+		}
+	}
+	return lbls
+}
+
+// LookupLabelsForAddr returns the CIDR labels of every prefix in the trie
+// that contains addr, from the longest match up to (and including) /0, plus
+// the reserved:world label. It is the reverse-direction counterpart to
+// GetCIDRLabels: given a packet's source address, it enumerates the CIDR
+// identities that address belongs to without rescanning the ipcache.
+func LookupLabelsForAddr(addr netip.Addr) labels.Labels {
+	node := globalTrie.lookup(addr, addr.BitLen())
+	lbls := ancestorLabels(node)
+	addWorldLabel(addr, lbls)
+	addGeoIPLabels(addr, lbls)
+	return lbls
+}
+
+// LookupLabelsForPrefix returns the same set of labels GetCIDRLabels does
+// for p. Ancestor nodes already in the trie are reused as they are; any
+// that are missing are computed and linked in (subject to maxTrieNodes).
+func LookupLabelsForPrefix(p netip.Prefix) labels.Labels {
+	return GetCIDRLabels(p)
+}
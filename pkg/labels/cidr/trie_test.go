@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package cidr
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestLookupLabelsForAddrMatchesGetCIDRLabels(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.1.2.0/24")
+	want := GetCIDRLabels(prefix)
+
+	got := LookupLabelsForAddr(netip.MustParseAddr("10.1.2.42"))
+	for key := range want {
+		if _, ok := got[key]; !ok {
+			t.Errorf("LookupLabelsForAddr: missing label %q present in GetCIDRLabels(%s)", key, prefix)
+		}
+	}
+}
+
+func TestGetCIDRLabelsSharesAncestorNodes(t *testing.T) {
+	GetCIDRLabels(netip.MustParsePrefix("10.0.0.0/8"))
+
+	globalTrie.mu.Lock()
+	node := globalTrie.root4
+	for i := 0; i < 8; i++ {
+		bit := bitAt(netip.MustParseAddr("10.0.0.0").AsSlice(), i)
+		node = node.children[bit]
+		if node == nil {
+			t.Fatalf("expected trie node at depth %d to exist after GetCIDRLabels", i+1)
+		}
+	}
+	eightNode := node
+	globalTrie.mu.Unlock()
+
+	GetCIDRLabels(netip.MustParsePrefix("10.1.0.0/16"))
+
+	globalTrie.mu.Lock()
+	defer globalTrie.mu.Unlock()
+	node = globalTrie.root4
+	for i := 0; i < 8; i++ {
+		bit := bitAt(netip.MustParseAddr("10.1.0.0").AsSlice(), i)
+		node = node.children[bit]
+		if node == nil {
+			t.Fatalf("expected trie node at depth %d to exist after second GetCIDRLabels", i+1)
+		}
+	}
+	if node != eightNode {
+		t.Errorf("10.0.0.0/8 and 10.1.0.0/16 should share the same /8 ancestor node, got distinct nodes")
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package option
+
+import "github.com/spf13/pflag"
+
+const (
+	// GeoIPDatabasePathName is the name of the option to configure the
+	// path to a MaxMind-format (.mmdb) GeoIP database used to derive
+	// reserved:geoip-country/continent labels for CIDR identities.
+	GeoIPDatabasePathName = "geoip-database-path"
+)
+
+// DaemonConfig is the configuration used by the Cilium daemon.
+//
+// This only carries the fields needed by pkg/labels/cidr today; the rest of
+// the daemon's configuration surface is intentionally not reproduced here.
+type DaemonConfig struct {
+	// EnableIPv4 and EnableIPv6 determine whether IsDualStack reports the
+	// daemon as running in dual-stack mode.
+	EnableIPv4 bool
+	EnableIPv6 bool
+
+	// GeoIPDatabasePath is the path to a MaxMind-format (.mmdb) GeoIP
+	// database. Empty disables GeoIP-derived labels.
+	GeoIPDatabasePath string
+}
+
+// Config is the global configuration instance used throughout the daemon.
+var Config = &DaemonConfig{
+	EnableIPv4: true,
+}
+
+// IsDualStack returns true if both IPv4 and IPv6 are enabled.
+func (c *DaemonConfig) IsDualStack() bool {
+	return c.EnableIPv4 && c.EnableIPv6
+}
+
+// Flags registers the command-line flags backing DaemonConfig fields that
+// pkg/labels/cidr depends on. Callers (e.g. the daemon's root command) are
+// expected to call this alongside the rest of the daemon's flag
+// registration and bind it with viper the same way every other option is
+// bound.
+func Flags(flags *pflag.FlagSet) {
+	flags.String(GeoIPDatabasePathName, "", "Path to a MaxMind-format (.mmdb) GeoIP database used to derive reserved:geoip-country/continent labels")
+}